@@ -1,7 +1,10 @@
-// Package csv provides functionality for decoding rows of a CSV file into a struct.
-package csv
+// Package csvdecoder provides functionality for decoding rows of a CSV file into a struct.
+package csvdecoder
 
 import (
+	"bytes"
+	"encoding"
+	"encoding/csv"
 	"errors"
 	"fmt"
 	"io"
@@ -25,6 +28,14 @@ type Reader interface {
 // AssignFn is the signature for custom assign functions
 type AssignFn func(s string, v reflect.Value, tag reflect.StructTag) error
 
+// CSVUnmarshaler is implemented by types that know how to decode a single CSV field into
+// themselves, analogous to encoding.TextUnmarshaler. unmarshall consults a field's CSVUnmarshaler
+// implementation, if any, before falling back to the kind-based assigner map -- letting types like
+// a UUID, a decimal, or an enum be decoded without registering a global AssignFn for their kind.
+type CSVUnmarshaler interface {
+	UnmarshalCSV(s string, tag reflect.StructTag) error
+}
+
 // A Decoder reads input from a Reader and parses the values into a struct.
 //
 // The decoder supports the following struct field types by default:
@@ -33,10 +44,25 @@ type AssignFn func(s string, v reflect.Value, tag reflect.StructTag) error
 // are treated as the nil value of the given type (ie 0 for numeric types). Additional types, or modification
 // of the default behaviour, can be overridden by setting custom assign functions.
 type Decoder struct {
-	Indexes   map[string]int
+	Indexes map[string]int
+
+	// Map, if set, is applied to every header cell read by ReadHeader before it is stored in
+	// Indexes, and to every struct field's csv name before it is looked up in Indexes. This lets
+	// headers that differ from struct names by case, whitespace or punctuation (eg "First Name" vs
+	// FirstName) match without an explicitly supplied Indexes map.
+	Map func(string) string
+
+	// From and To restrict which records Decode, DecodeAll and DecodeEach process, counting data
+	// records from 1 (the header, if any, is not counted). Records before From are skipped, and
+	// decoding stops -- returning io.EOF -- once a record past To is reached. A value of 0 for
+	// either means unbounded.
+	From int
+	To   int
+
 	assigners map[reflect.Kind]AssignFn
 	r         Reader
 	line      int
+	record    int
 }
 
 // NewDecoder returns a new Decoder instance using r as its source
@@ -67,6 +93,9 @@ func (this *Decoder) ReadHeader() error {
 	} else {
 		this.Indexes = make(map[string]int)
 		for i, d := range data {
+			if this.Map != nil {
+				d = this.Map(d)
+			}
 			this.Indexes[d] = i
 		}
 	}
@@ -74,72 +103,303 @@ func (this *Decoder) ReadHeader() error {
 }
 
 // Decode reads the next values from its reader, parses the data and stores the result in the value
-// pointed to by dst. If the internal reader returns an error, that error is returned.
+// pointed to by dst. If the internal reader returns an error, that error is returned. Records before
+// From are read and silently skipped; once a record past To is reached, io.EOF is returned without
+// reading further.
 //
 // There are multiple ways in which the values read can be decoded into the struct. See the examples for
 // the possible options.
 func (this *Decoder) Decode(dst interface{}) error {
-	this.line += 1
-	if data, err := this.r.Read(); err != nil {
-		return err
-	} else {
-		if err = unmarshall(data, this.Indexes, this.assigners, dst); err != nil && err != io.EOF {
-			return fmt.Errorf("csv: Error on line %d: %v", this.line, err)
+	for {
+		this.line += 1
+		this.record += 1
+		if this.To > 0 && this.record > this.To {
+			return io.EOF
+		}
+		data, err := this.r.Read()
+		if err != nil {
+			return err
+		}
+		if this.From > 0 && this.record < this.From {
+			continue
+		}
+		if err = unmarshall(data, this.Indexes, this.assigners, this.Map, dst); err != nil && err != io.EOF {
+			return fmt.Errorf("csvdecoder: Error on line %d: %v", this.line, err)
 		}
 		return nil
 	}
 }
 
-func unmarshall(data []string, indexes map[string]int, assigners map[reflect.Kind]AssignFn, dst interface{}) error {
+// DecodeEach decodes all remaining records (subject to From and To) and sends each one onto ch,
+// which must be a chan T or chan<- T for some struct type T. ch is closed once Decode returns
+// io.EOF (the underlying Reader is exhausted or To is passed); any other decoding error aborts the
+// loop and is returned, leaving ch open.
+func (this *Decoder) DecodeEach(ch interface{}) error {
+	chVal := reflect.ValueOf(ch)
+	if chVal.Kind() != reflect.Chan || chVal.Type().ChanDir()&reflect.SendDir == 0 {
+		return errors.New("csvdecoder: ch is not a sendable channel")
+	}
+	elemType := chVal.Type().Elem()
+
+	for {
+		elem := reflect.New(elemType)
+		if err := this.Decode(elem.Interface()); err == io.EOF {
+			chVal.Close()
+			return nil
+		} else if err != nil {
+			return err
+		}
+		chVal.Send(elem.Elem())
+	}
+}
+
+// DecodeAll reads a header row (unless Indexes is already set, by ReadHeader or directly) and then decodes all
+// remaining records from the decoder's Reader, appending each to the slice pointed to by dst.
+// dst must be a non-nil pointer to a slice of structs.
+func (this *Decoder) DecodeAll(dst interface{}) error {
+	slicePtr := reflect.ValueOf(dst)
+	if slicePtr.Kind() != reflect.Ptr || slicePtr.IsNil() {
+		return errors.New("csvdecoder: dst is not a non-nil pointer")
+	}
+	slice := slicePtr.Elem()
+	if slice.Kind() != reflect.Slice {
+		return errors.New("csvdecoder: dst is not a pointer to a slice")
+	}
+	elemType := slice.Type().Elem()
+
+	if this.Indexes == nil {
+		if err := this.ReadHeader(); err != nil && err != io.EOF {
+			return err
+		} else if err == io.EOF {
+			return nil
+		}
+	}
+
+	for {
+		elem := reflect.New(elemType)
+		if err := this.Decode(elem.Interface()); err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		slice.Set(reflect.Append(slice, elem.Elem()))
+	}
+	return nil
+}
+
+// Unmarshal parses CSV-encoded data, using the first record as a header matched against the csv
+// tags of dst's element type, and stores the result in the slice pointed to by dst. It is a
+// convenience wrapper around NewDecoder and Decoder.DecodeAll for the common case of decoding an
+// entire file at once.
+func Unmarshal(data []byte, dst interface{}) error {
+	r := csv.NewReader(bytes.NewReader(data))
+	return NewDecoder(r).DecodeAll(dst)
+}
+
+func unmarshall(data []string, indexes map[string]int, assigners map[reflect.Kind]AssignFn, mapFn func(string) string, dst interface{}) error {
 	val := reflect.ValueOf(dst)
 	if val.Kind() != reflect.Ptr {
-		return errors.New("csv: dst is not a pointer")
+		return errors.New("csvdecoder: dst is not a pointer")
 	}
 	if val.IsNil() {
-		return errors.New("csv: dst is nil")
+		return errors.New("csvdecoder: dst is nil")
 	}
 	e := val.Elem()
-	n := e.NumField()
 
 	// If indexes is not specified, field number i of dst gets assigned to data[i].
-	// If the number of fields in dst and number of rows in data is inequal, we treat it as an error
-	if indexes == nil && n != len(data) {
-		return errors.New("csv: struct field count didn't match data column count")
+	if indexes == nil {
+		if e.NumField() != len(data) {
+			return errors.New("csvdecoder: struct field count didn't match data column count")
+		}
+		return unmarshallPositional(data, assigners, e)
 	}
 
+	used := make(map[int]bool, len(data))
+	var sliceFields []reflect.Value
+	if err := unmarshallFields(data, indexes, assigners, mapFn, e, "", used, &sliceFields); err != nil {
+		return err
+	}
+	return unmarshallSliceColumns(data, assigners, used, sliceFields)
+}
+
+// unmarshallPositional assigns data[i] to the i'th field of e, in struct declaration order.
+func unmarshallPositional(data []string, assigners map[reflect.Kind]AssignFn, e reflect.Value) error {
 	t := e.Type()
-	for i := 0; i < n; i++ {
+	for i := 0; i < e.NumField(); i++ {
 		f := t.Field(i)
-		var dataIndex int
-		if indexes != nil {
-			if dataIndex = fieldIndex(f, indexes); dataIndex == -1 {
-				continue
-			}
-		} else {
-			dataIndex = i
+		v, skip := derefPtr(e.Field(i), data[i])
+		if skip {
+			continue
 		}
+		if err := unmarshallField(data[i], v, f.Tag, assigners); err != nil {
+			return wrapFieldError(f.Name, err)
+		}
+	}
+	return nil
+}
 
-		s := data[dataIndex]
+// unmarshallFields walks e's fields, matching each against indexes by name (prefixed by prefix, for
+// fields reached through an `inline` struct). Anonymous embedded structs are recursed into directly,
+// promoting their fields into e's own header matching. Slice-typed fields (other than []byte) are
+// not matched by name; they're appended to *sliceFields for unmarshallSliceColumns to fill in once
+// every named field has claimed its column.
+func unmarshallFields(data []string, indexes map[string]int, assigners map[reflect.Kind]AssignFn, mapFn func(string) string, e reflect.Value, prefix string, used map[int]bool, sliceFields *[]reflect.Value) error {
+	t := e.Type()
+	for i := 0; i < e.NumField(); i++ {
+		f := t.Field(i)
 		v := e.Field(i)
 
-		if a, ok := assigners[v.Kind()]; ok {
-			if err := a(s, v, f.Tag); err != nil {
-				if err == ErrUseDefault {
-					if a, ok := defaultAssigners[v.Kind()]; ok {
-						err = a(s, v, f.Tag)
-					}
-				}
-				if err != nil {
-					return fmt.Errorf("csv: error assigning value to field %s: %v", f.Name, err)
-				}
+		if f.Anonymous && v.Kind() == reflect.Struct && v.Type() != timeType {
+			if err := unmarshallFields(data, indexes, assigners, mapFn, v, prefix, used, sliceFields); err != nil {
+				return err
 			}
-		} else {
-			return fmt.Errorf("csv: unassignable field type for field %s: %v", f.Name, v.Kind())
+			continue
+		}
+
+		name, inline := fieldTagOptions(f)
+		if inline {
+			if v.Kind() != reflect.Struct {
+				return fmt.Errorf("csvdecoder: inline tag used on non-struct field %s", f.Name)
+			}
+			if err := unmarshallFields(data, indexes, assigners, mapFn, v, prefix+name, used, sliceFields); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+			*sliceFields = append(*sliceFields, v)
+			continue
+		}
+
+		fullName := prefix + name
+		if mapFn != nil {
+			fullName = mapFn(fullName)
+		}
+		dataIndex, ok := indexes[fullName]
+		if !ok {
+			continue
+		}
+		used[dataIndex] = true
+
+		fv, skip := derefPtr(v, data[dataIndex])
+		if skip {
+			continue
+		}
+		if err := unmarshallField(data[dataIndex], fv, f.Tag, assigners); err != nil {
+			return wrapFieldError(f.Name, err)
 		}
 	}
 	return nil
 }
 
+// unmarshallSliceColumns fills each of sliceFields with the columns of data that no named field
+// claimed, in column order. If more than one slice field is present, the first one (in struct
+// declaration order) claims all of them and the rest are left empty.
+func unmarshallSliceColumns(data []string, assigners map[reflect.Kind]AssignFn, used map[int]bool, sliceFields []reflect.Value) error {
+	if len(sliceFields) == 0 {
+		return nil
+	}
+	var remaining []int
+	for i := range data {
+		if !used[i] {
+			remaining = append(remaining, i)
+			used[i] = true
+		}
+	}
+	for _, v := range sliceFields {
+		elemType := v.Type().Elem()
+		slice := reflect.MakeSlice(v.Type(), len(remaining), len(remaining))
+		for j, dataIndex := range remaining {
+			elem := reflect.New(elemType).Elem()
+			if err := unmarshallField(data[dataIndex], elem, "", assigners); err != nil {
+				return wrapFieldError(fmt.Sprintf("%s[%d]", v.Type(), j), err)
+			}
+			slice.Index(j).Set(elem)
+		}
+		v.Set(slice)
+		remaining = nil
+	}
+	return nil
+}
+
+// derefPtr prepares v for assignment of s. If v is not a pointer, it's returned unchanged. If it is
+// a pointer, an empty s leaves it nil (skip is true); otherwise it's allocated (if nil) and the
+// pointed-to value is returned for the caller to assign into.
+func derefPtr(v reflect.Value, s string) (elem reflect.Value, skip bool) {
+	if v.Kind() != reflect.Ptr {
+		return v, false
+	}
+	if s == "" {
+		return v, true
+	}
+	if v.IsNil() {
+		v.Set(reflect.New(v.Type().Elem()))
+	}
+	return v.Elem(), false
+}
+
+// unmarshallField decodes s into v, using tag for any format or option info. v's CSVUnmarshaler
+// implementation, if any, is consulted first, then the kind-based assigner map (falling back to the
+// default assigner for the kind on ErrUseDefault), then finally v's encoding.TextUnmarshaler
+// implementation.
+func unmarshallField(s string, v reflect.Value, tag reflect.StructTag, assigners map[reflect.Kind]AssignFn) error {
+	if handled, err := unmarshalCSV(s, v, tag); handled {
+		return err
+	}
+
+	if a, ok := assigners[v.Kind()]; ok {
+		if err := a(s, v, tag); err != nil {
+			if err == ErrUseDefault {
+				if a, ok := defaultAssigners[v.Kind()]; ok {
+					return a(s, v, tag)
+				}
+				return nil
+			}
+			return err
+		}
+		return nil
+	}
+
+	if u, ok := textUnmarshaler(v); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+	return errUnassignable{v.Kind()}
+}
+
+// errUnassignable is returned by unmarshallField when a field's kind has no registered assigner and
+// implements neither CSVUnmarshaler nor encoding.TextUnmarshaler. wrapFieldError gives it a message
+// distinct from a plain assignment failure.
+type errUnassignable struct{ kind reflect.Kind }
+
+func (e errUnassignable) Error() string {
+	return e.kind.String()
+}
+
+// wrapFieldError annotates err, returned by unmarshallField for the named field, with that field's
+// name, in a form matching how the field was unassignable vs how its value failed to parse.
+func wrapFieldError(name string, err error) error {
+	if ua, ok := err.(errUnassignable); ok {
+		return fmt.Errorf("csvdecoder: unassignable field type for field %s: %v", name, ua.kind)
+	}
+	return fmt.Errorf("csvdecoder: error assigning value to field %s: %v", name, err)
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// fieldTagOptions returns the field's CSV name -- as per fieldName -- and whether its csv tag
+// includes the `inline` option, used to decode a nested struct field as a group of prefixed columns.
+func fieldTagOptions(f reflect.StructField) (name string, inline bool) {
+	name = fieldName(f)
+	tag := f.Tag.Get("csv")
+	for _, opt := range strings.Split(tag, ",")[1:] {
+		if opt == "inline" {
+			inline = true
+		}
+	}
+	return name, inline
+}
+
 var defaultAssigners = getDefaultAssigners()
 
 func getDefaultAssigners() map[reflect.Kind]AssignFn {
@@ -227,20 +487,42 @@ func assignStruct(s string, v reflect.Value, tag reflect.StructTag) error {
 			return fmt.Errorf("missing format info in tag")
 		}
 	default:
+		if u, ok := textUnmarshaler(v); ok {
+			return u.UnmarshalText([]byte(s))
+		}
 		return fmt.Errorf("unsupported struct type: %s", v.Kind())
 	}
 }
 
-func fieldIndex(f reflect.StructField, indexes map[string]int) int {
-	var name string
-	if tag := f.Tag.Get("csv"); tag != "" {
-		name = strings.Split(tag, ",")[0]
-	} else {
-		name = f.Name
+// unmarshalCSV checks v for a CSVUnmarshaler implementation and, if found, uses it to decode s.
+// handled is false if v doesn't implement CSVUnmarshaler, in which case the caller should fall back
+// to the kind-based assigner map.
+func unmarshalCSV(s string, v reflect.Value, tag reflect.StructTag) (handled bool, err error) {
+	if !v.CanAddr() {
+		return false, nil
 	}
-	if i, ok := indexes[name]; ok {
-		return i
-	} else {
-		return -1
+	if u, ok := v.Addr().Interface().(CSVUnmarshaler); ok {
+		return true, u.UnmarshalCSV(s, tag)
+	}
+	return false, nil
+}
+
+// textUnmarshaler returns v's encoding.TextUnmarshaler implementation, if any. It is consulted as
+// a fallback, after CSVUnmarshaler, for types -- such as a UUID or a decimal -- that don't have a
+// registered assigner for their kind, and by assignStruct for struct types other than time.Time.
+func textUnmarshaler(v reflect.Value) (encoding.TextUnmarshaler, bool) {
+	if !v.CanAddr() {
+		return nil, false
+	}
+	u, ok := v.Addr().Interface().(encoding.TextUnmarshaler)
+	return u, ok
+}
+
+// fieldName returns the CSV column name for f: the name portion of its csv tag, if any,
+// falling back to the field's own name.
+func fieldName(f reflect.StructField) string {
+	if tag := f.Tag.Get("csv"); tag != "" {
+		return strings.Split(tag, ",")[0]
 	}
+	return f.Name
 }
@@ -2,7 +2,10 @@ package csvdecoder
 
 import (
 	"fmt"
+	"io"
 	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -30,10 +33,11 @@ func TestUnmarshall(t *testing.T) {
 	var nilDst *thing
 	tests := []struct {
 		name string
-		data []string
-		idx  map[string]int
-		ass  map[reflect.Kind]AssignFn
-		dst  interface{}
+		data  []string
+		idx   map[string]int
+		ass   map[reflect.Kind]AssignFn
+		mapFn func(string) string
+		dst   interface{}
 		want interface{}
 		err  error
 	}{
@@ -57,7 +61,7 @@ func TestUnmarshall(t *testing.T) {
 			idx:  nil,
 			dst:  &thing{},
 			want: &thing{},
-			err:  fmt.Errorf("struct field count didn't match data column count"),
+			err:  fmt.Errorf("csvdecoder: struct field count didn't match data column count"),
 		},
 		{
 			name: "if no indexes, load fields according their index index in the struct",
@@ -145,12 +149,20 @@ func TestUnmarshall(t *testing.T) {
 			dst:  &thing{},
 			want: &thing{"str", 1, 1.5},
 		},
+		{
+			name:  "mapFn normalizes field names before matching against indexes",
+			data:  []string{"str", "1", "1.5"},
+			idx:   map[string]int{"s": 0, "i": 1, "f": 2},
+			mapFn: strings.ToLower,
+			dst:   &thing{},
+			want:  &thing{"str", 1, 1.5},
+		},
 	}
 	for _, test := range tests {
 		if test.ass == nil {
 			test.ass = defaultAssigners
 		}
-		err := unmarshall(test.data, test.idx, test.ass, test.dst)
+		err := unmarshall(test.data, test.idx, test.ass, test.mapFn, test.dst)
 		if !reflect.DeepEqual(test.err, err) {
 			t.Errorf("%s: Got error '%v', want '%v'", test.name, err, test.err)
 		}
@@ -198,3 +210,136 @@ func TestAssignStruct(t *testing.T) {
 		}
 	}
 }
+
+type upperString string
+
+func (u *upperString) UnmarshalCSV(s string, tag reflect.StructTag) error {
+	*u = upperString(strings.ToUpper(s))
+	return nil
+}
+
+// rangeField is a struct other than time.Time, so the default struct assigner decodes it through
+// its TextUnmarshaler implementation.
+type rangeField struct {
+	Lo, Hi int
+}
+
+func (r *rangeField) UnmarshalText(b []byte) error {
+	parts := strings.SplitN(string(b), "-", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("invalid range %q", b)
+	}
+	lo, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return err
+	}
+	hi, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return err
+	}
+	r.Lo, r.Hi = lo, hi
+	return nil
+}
+
+func TestUnmarshalCustom(t *testing.T) {
+	type thing struct {
+		U upperString
+		R rangeField
+	}
+	dst := &thing{}
+	err := unmarshall([]string{"hi", "1-5"}, nil, defaultAssigners, nil, dst)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := &thing{"HI", rangeField{1, 5}}
+	if !reflect.DeepEqual(want, dst) {
+		t.Errorf("got %+v, want %+v", dst, want)
+	}
+}
+
+func TestUnmarshallEmbeddedPointerInlineSlice(t *testing.T) {
+	type Address struct {
+		City string `csv:"city"`
+		Zip  string `csv:"zip"`
+	}
+	type Base struct {
+		ID int `csv:"id"`
+	}
+	type Person struct {
+		Base
+		Name  string  `csv:"name"`
+		Age   *int    `csv:"age"`
+		Addr  Address `csv:"addr_,inline"`
+		Extra []string
+	}
+
+	indexes := map[string]int{
+		"id": 0, "name": 1, "age": 2, "addr_city": 3, "addr_zip": 4, "extra1": 5, "extra2": 6,
+	}
+
+	// embedded, inline and slice fields, with an empty pointer field left nil
+	data := []string{"7", "John", "", "Springfield", "00000", "x", "y"}
+	dst := &Person{}
+	if err := unmarshall(data, indexes, defaultAssigners, nil, dst); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dst.Base.ID != 7 {
+		t.Errorf("embedded field ID = %d, want 7", dst.Base.ID)
+	}
+	if dst.Name != "John" {
+		t.Errorf("Name = %q, want John", dst.Name)
+	}
+	if dst.Age != nil {
+		t.Errorf("Age = %v, want nil for empty string", dst.Age)
+	}
+	if dst.Addr.City != "Springfield" || dst.Addr.Zip != "00000" {
+		t.Errorf("Addr = %+v, want {Springfield 00000}", dst.Addr)
+	}
+	if !reflect.DeepEqual(dst.Extra, []string{"x", "y"}) {
+		t.Errorf("Extra = %v, want [x y]", dst.Extra)
+	}
+
+	// a non-empty pointer field is allocated and assigned
+	data2 := []string{"7", "John", "30", "Springfield", "00000", "x", "y"}
+	dst2 := &Person{}
+	if err := unmarshall(data2, indexes, defaultAssigners, nil, dst2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dst2.Age == nil || *dst2.Age != 30 {
+		t.Errorf("Age = %v, want pointer to 30", dst2.Age)
+	}
+}
+
+// sliceReader is a minimal Reader implementation backed by a fixed set of records, for tests that
+// don't need a real CSV parser.
+type sliceReader struct {
+	records [][]string
+}
+
+func (r *sliceReader) Read() ([]string, error) {
+	if len(r.records) == 0 {
+		return nil, io.EOF
+	}
+	record := r.records[0]
+	r.records = r.records[1:]
+	return record, nil
+}
+
+func TestDecodeAllWithPreSetIndexes(t *testing.T) {
+	type Student struct {
+		Name string
+		Age  int
+	}
+	r := &sliceReader{records: [][]string{{"John", "21"}, {"Sue", "24"}}}
+	decoder := NewDecoder(r)
+	decoder.Indexes = map[string]int{"Name": 0, "Age": 1}
+
+	var students []Student
+	if err := decoder.DecodeAll(&students); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []Student{{"John", 21}, {"Sue", 24}}
+	if !reflect.DeepEqual(want, students) {
+		t.Errorf("got %+v, want %+v", students, want)
+	}
+}
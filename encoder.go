@@ -0,0 +1,178 @@
+package csvdecoder
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Writer is the interface used by the encoder to write CSV output line by line.
+//
+// Write takes a single line of values, each already formatted as a string.
+type Writer interface {
+	Write(record []string) error
+}
+
+// MarshalFn is the signature for custom marshal functions
+type MarshalFn func(v reflect.Value, tag reflect.StructTag) (string, error)
+
+// An Encoder writes structs to an output Writer, serializing each field to a string.
+//
+// The encoder supports the following struct field types by default:
+// int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, uintptr, float32, float64, string,
+// time.Time (requires custom attribute -- see example). Pointer, slice, embedded and inline struct fields,
+// and CSVUnmarshaler or TextUnmarshaler types, are not handled by the default marshal functions. A zero
+// time.Time is written out as the empty string; zero values of other types are written in their usual
+// form (eg 0 for numeric types). Additional types, or modification of the default behaviour, can be
+// overridden by setting custom marshal functions.
+type Encoder struct {
+	marshalers map[reflect.Kind]MarshalFn
+	w          Writer
+	line       int
+}
+
+// NewEncoder returns a new Encoder instance writing to w
+func NewEncoder(w Writer) *Encoder {
+	return &Encoder{
+		w:          w,
+		marshalers: getDefaultMarshalers(),
+	}
+}
+
+// SetMarshalFn sets the given function as the marshal function for fields of the given kind, overriding
+// any previous behaviour for that kind.
+func (this *Encoder) SetMarshalFn(kind reflect.Kind, fn MarshalFn) {
+	this.marshalers[kind] = fn
+}
+
+// WriteHeader writes a header row built from v's field names, using the same `csv` tags that Decode
+// matches against.
+//
+// WriteHeader is optional, but if used, should be called only once, and before any calls to Encode()
+func (this *Encoder) WriteHeader(v interface{}) error {
+	this.line += 1
+	names, err := fieldNames(v)
+	if err != nil {
+		return fmt.Errorf("csvdecoder: Error on line %d: %v", this.line, err)
+	}
+	return this.w.Write(names)
+}
+
+// Encode serializes v's fields to strings and writes them as the next record of its writer.
+func (this *Encoder) Encode(v interface{}) error {
+	this.line += 1
+	data, err := marshall(v, this.marshalers)
+	if err != nil {
+		return fmt.Errorf("csvdecoder: Error on line %d: %v", this.line, err)
+	}
+	return this.w.Write(data)
+}
+
+func marshall(src interface{}, marshalers map[reflect.Kind]MarshalFn) ([]string, error) {
+	val := reflect.ValueOf(src)
+	if val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return nil, errors.New("csvdecoder: src is nil")
+		}
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return nil, errors.New("csvdecoder: src is not a struct")
+	}
+
+	t := val.Type()
+	n := val.NumField()
+	data := make([]string, n)
+	for i := 0; i < n; i++ {
+		f := t.Field(i)
+		v := val.Field(i)
+
+		if m, ok := marshalers[v.Kind()]; ok {
+			s, err := m(v, f.Tag)
+			if err != nil {
+				if err == ErrUseDefault {
+					if m, ok := defaultMarshalers[v.Kind()]; ok {
+						s, err = m(v, f.Tag)
+					}
+				}
+				if err != nil {
+					return nil, fmt.Errorf("csvdecoder: error marshaling field %s: %v", f.Name, err)
+				}
+			}
+			data[i] = s
+		} else {
+			return nil, fmt.Errorf("csvdecoder: unmarshalable field type for field %s: %v", f.Name, v.Kind())
+		}
+	}
+	return data, nil
+}
+
+func fieldNames(src interface{}) ([]string, error) {
+	val := reflect.ValueOf(src)
+	if val.Kind() == reflect.Ptr {
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return nil, errors.New("csvdecoder: src is not a struct")
+	}
+
+	t := val.Type()
+	n := t.NumField()
+	names := make([]string, n)
+	for i := 0; i < n; i++ {
+		names[i] = fieldName(t.Field(i))
+	}
+	return names, nil
+}
+
+var defaultMarshalers = getDefaultMarshalers()
+
+func getDefaultMarshalers() map[reflect.Kind]MarshalFn {
+	dict := make(map[reflect.Kind]MarshalFn)
+	dict[reflect.String] = marshalString
+	dict[reflect.Struct] = marshalStruct
+	for _, kind := range []reflect.Kind{reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64} {
+		dict[kind] = marshalInt
+	}
+	for _, kind := range []reflect.Kind{reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr} {
+		dict[kind] = marshalUint
+	}
+	for _, kind := range []reflect.Kind{reflect.Float32, reflect.Float64} {
+		dict[kind] = marshalFloat
+	}
+	return dict
+}
+
+func marshalString(v reflect.Value, tag reflect.StructTag) (string, error) {
+	return v.String(), nil
+}
+
+func marshalInt(v reflect.Value, tag reflect.StructTag) (string, error) {
+	return strconv.FormatInt(v.Int(), 10), nil
+}
+
+func marshalUint(v reflect.Value, tag reflect.StructTag) (string, error) {
+	return strconv.FormatUint(v.Uint(), 10), nil
+}
+
+func marshalFloat(v reflect.Value, tag reflect.StructTag) (string, error) {
+	return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()), nil
+}
+
+func marshalStruct(v reflect.Value, tag reflect.StructTag) (string, error) {
+	switch t := v.Interface().(type) {
+	case time.Time:
+		if t.IsZero() {
+			return "", nil
+		}
+		if split := strings.Split(tag.Get("csv"), ","); len(split) > 1 {
+			return t.Format(split[1]), nil
+		}
+		return "", fmt.Errorf("missing format info in tag")
+	default:
+		return "", fmt.Errorf("unsupported struct type: %s", v.Kind())
+	}
+}
@@ -0,0 +1,118 @@
+package csvdecoder
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMarshall(t *testing.T) {
+	type thing struct {
+		S string
+		I int64
+		F float64
+	}
+	type taggedThing struct {
+		S string  `csv:"myString"`
+		I int64   `csv:"int"`
+		F float64 `csv:"float-prop"`
+	}
+	type timeThing struct {
+		D time.Time
+	}
+	type taggedTimeThing struct {
+		D time.Time `csv:",2006-01-02"`
+	}
+	var nilSrc *thing
+	tests := []struct {
+		name string
+		src  interface{}
+		mar  map[reflect.Kind]MarshalFn
+		want []string
+		err  error
+	}{
+		{
+			name: "error if src is not a struct",
+			src:  "not a struct",
+			err:  fmt.Errorf("csvdecoder: src is not a struct"),
+		},
+		{
+			name: "error if src is nil",
+			src:  nilSrc,
+			err:  fmt.Errorf("csvdecoder: src is nil"),
+		},
+		{
+			name: "marshals fields in struct order",
+			src:  &thing{"str", 1, 1.5},
+			want: []string{"str", "1", "1.5"},
+		},
+		{
+			name: "tags don't affect marshaling order",
+			src:  &taggedThing{"str", 1, 1.5},
+			want: []string{"str", "1", "1.5"},
+		},
+		{
+			name: "error if field type is time and tag doesn't contain format",
+			src:  &timeThing{time.Date(1988, time.November, 8, 0, 0, 0, 0, time.UTC)},
+			err:  fmt.Errorf("csvdecoder: error marshaling field D: missing format info in tag"),
+		},
+		{
+			name: "marshals time according to format in tag",
+			src:  &taggedTimeThing{time.Date(1988, time.November, 8, 0, 0, 0, 0, time.UTC)},
+			want: []string{"1988-11-08"},
+		},
+		{
+			name: "zero time marshals to empty string",
+			src:  &taggedTimeThing{},
+			want: []string{""},
+		},
+		{
+			name: "error if no marshaler for field kind",
+			src:  &thing{"str", 1, 1.5},
+			mar: map[reflect.Kind]MarshalFn{
+				reflect.Int64:   defaultMarshalers[reflect.Int64],
+				reflect.Float64: defaultMarshalers[reflect.Float64],
+			},
+			err: fmt.Errorf("csvdecoder: unmarshalable field type for field S: string"),
+		},
+		{
+			name: "falls back to default marshal function if marshalFn returns ErrUseDefault",
+			src:  &thing{"str", 1, 1.5},
+			mar: map[reflect.Kind]MarshalFn{
+				reflect.Int64:   defaultMarshalers[reflect.Int64],
+				reflect.Float64: defaultMarshalers[reflect.Float64],
+				reflect.String:  func(v reflect.Value, t reflect.StructTag) (string, error) { return "", ErrUseDefault },
+			},
+			want: []string{"str", "1", "1.5"},
+		},
+	}
+	for _, test := range tests {
+		if test.mar == nil {
+			test.mar = defaultMarshalers
+		}
+		got, err := marshall(test.src, test.mar)
+		if !reflect.DeepEqual(test.err, err) {
+			t.Errorf("%s: Got error '%v', want '%v'", test.name, err, test.err)
+		}
+		if test.err == nil && !reflect.DeepEqual(test.want, got) {
+			t.Errorf("%s: marshalled to %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestFieldNames(t *testing.T) {
+	type taggedThing struct {
+		S string  `csv:"myString"`
+		I int64   `csv:"int"`
+		F float64 `csv:"float-prop"`
+	}
+	names, err := fieldNames(&taggedThing{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"myString", "int", "float-prop"}
+	if !reflect.DeepEqual(want, names) {
+		t.Errorf("got %v, want %v", names, want)
+	}
+}
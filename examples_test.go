@@ -4,6 +4,8 @@ import (
 	"encoding/csv"
 	"fmt"
 	"io"
+	"os"
+	"reflect"
 	"strings"
 	"time"
 )
@@ -124,16 +126,41 @@ func ExampleDecoder_Decode_attributes() {
 	// Name: Susan, Age: 24
 }
 
-// The example shows how to use attributes to specify the time format to parse datetime values
-func ExampleDecoder_Decode_time() {
+// The example shows that decoding an empty file succeeds with an empty slice, rather than
+// surfacing the underlying io.EOF from reading the (absent) header.
+func ExampleUnmarshal_empty() {
 	type Student struct {
-		Name     string
-		Birthday time.Time `csv:",2006-01-02"`
+		Name string
+		Age  int
 	}
-	const input = "John,1994-05-14\nSusan,1991-12-03"
+	var students []Student
+	if err := Unmarshal([]byte(""), &students); err != nil {
+		fmt.Printf("%v", err)
+		return
+	}
+	fmt.Printf("%d students\n", len(students))
+	// Output: 0 students
+}
+
+// The example shows how to use Map to normalize header cells before they're matched against
+// struct field names, so that headers like "First Name" decode into a FirstName field without an
+// explicitly supplied Indexes map.
+func ExampleDecoder_Decode_map() {
+	type Student struct {
+		FirstName string
+		Age       int
+	}
+	const input = "First Name,Age\nJohn,21\nSusan,24"
 	r := csv.NewReader(strings.NewReader(input))
 	decoder := NewDecoder(r)
+	decoder.Map = func(s string) string {
+		return strings.ReplaceAll(s, " ", "")
+	}
 
+	if err := decoder.ReadHeader(); err != nil {
+		fmt.Printf("%v", err)
+		return
+	}
 	for {
 		var student Student
 		if err := decoder.Decode(&student); err == io.EOF {
@@ -142,61 +169,241 @@ func ExampleDecoder_Decode_time() {
 			fmt.Printf("%v", err)
 			return
 		}
-		fmt.Printf("Name: %s, Birthday: %s\n", student.Name, student.Birthday.Format("Jan 2, 2006"))
+		fmt.Printf("Name: %s, Age: %d\n", student.FirstName, student.Age)
 	}
-	// Output: Name: John, Birthday: May 14, 1994
-	// Name: Susan, Birthday: Dec 3, 1991
+	// Output: Name: John, Age: 21
+	// Name: Susan, Age: 24
 }
 
-// The example shows how to use Retry to parse the input into alternative structure
-// if the field count mismatches.
-// Be sure to set (csv.NewReader.)FieldsPerRecord = -1 so this works.
-func ExampleDecoder_Retry() {
+// The example shows how to use From and To to restrict decoding to a range of records, skipping
+// the rest of the file without materializing it.
+func ExampleDecoder_Decode_fromTo() {
 	type Student struct {
-		Name     string
-		Birthday time.Time `csv:",2006-01-02"`
+		Name string
+		Age  int
+	}
+	const input = "John,21\nSusan,24\nAmy,22\nBill,23"
+	r := csv.NewReader(strings.NewReader(input))
+	decoder := NewDecoder(r)
+	decoder.From = 2
+	decoder.To = 3
+
+	for {
+		var student Student
+		if err := decoder.Decode(&student); err == io.EOF {
+			break
+		} else if err != nil {
+			fmt.Printf("%v", err)
+			return
+		}
+		fmt.Printf("Name: %s, Age: %d\n", student.Name, student.Age)
+	}
+	// Output: Name: Susan, Age: 24
+	// Name: Amy, Age: 22
+}
+
+// The example shows how to use DecodeEach to stream decoded records into a channel, useful for
+// processing large files with backpressure.
+func ExampleDecoder_DecodeEach() {
+	type Student struct {
+		Name string
+		Age  int
+	}
+	const input = "John,21\nSusan,24"
+	r := csv.NewReader(strings.NewReader(input))
+	decoder := NewDecoder(r)
+
+	ch := make(chan Student)
+	go func() {
+		if err := decoder.DecodeEach(ch); err != nil {
+			fmt.Printf("%v", err)
+		}
+	}()
+	for student := range ch {
+		fmt.Printf("Name: %s, Age: %d\n", student.Name, student.Age)
+	}
+	// Output: Name: John, Age: 21
+	// Name: Susan, Age: 24
+}
+
+// The example shows how to implement CSVUnmarshaler to decode a field with custom logic, such as
+// an enum, without registering a global AssignFn for its kind.
+func ExampleDecoder_Decode_customUnmarshaler() {
+	type Account struct {
+		Name   string
+		Status statusField
+	}
+
+	const input = "John,active\nSusan,inactive"
+	r := csv.NewReader(strings.NewReader(input))
+	decoder := NewDecoder(r)
+
+	for {
+		var account Account
+		if err := decoder.Decode(&account); err == io.EOF {
+			break
+		} else if err != nil {
+			fmt.Printf("%v", err)
+			return
+		}
+		fmt.Printf("Name: %s, Status: %s\n", account.Name, account.Status)
+	}
+	// Output: Name: John, Status: active
+	// Name: Susan, Status: inactive
+}
+
+type statusField string
+
+func (s *statusField) UnmarshalCSV(v string, tag reflect.StructTag) error {
+	*s = statusField(v)
+	return nil
+}
+
+func (s statusField) String() string {
+	return string(s)
+}
+
+// The example shows how to decode into embedded structs, pointer fields, and an inline-tagged
+// nested struct whose columns are prefixed in the header.
+func ExampleDecoder_Decode_embedded() {
+	type Contact struct {
+		Email string
 	}
-	type Summary struct {
-		Count int
+	type Address struct {
+		City string
+		Zip  string
 	}
-	const input = "John,1994-05-14\nSusan,1991-12-03\n3"
+	type Student struct {
+		Contact
+		Name string
+		Age  *int
+		Home Address `csv:"home_,inline"`
+	}
+	const input = "Email,Name,Age,home_City,home_Zip\njohn@example.com,John,21,Springfield,00000\nsusan@example.com,Susan,,Shelbyville,00001"
 	r := csv.NewReader(strings.NewReader(input))
+	decoder := NewDecoder(r)
+
+	if err := decoder.ReadHeader(); err != nil {
+		fmt.Printf("%v", err)
+		return
+	}
+	for {
+		var student Student
+		if err := decoder.Decode(&student); err == io.EOF {
+			break
+		} else if err != nil {
+			fmt.Printf("%v", err)
+			return
+		}
+		age := "unknown"
+		if student.Age != nil {
+			age = fmt.Sprintf("%d", *student.Age)
+		}
+		fmt.Printf("Name: %s, Age: %s, Email: %s, City: %s\n", student.Name, age, student.Email, student.Home.City)
+	}
+	// Output: Name: John, Age: 21, Email: john@example.com, City: Springfield
+	// Name: Susan, Age: unknown, Email: susan@example.com, City: Shelbyville
+}
 
-	// since we're detecting the need for a retry based on the field count, tell the CSV reader
-	// that there are a variable number of fields per record
+// The example shows how an untagged []T field consumes every column not otherwise claimed by a
+// named field, useful for rows with a variable number of trailing values.
+func ExampleDecoder_Decode_sliceColumn() {
+	type Record struct {
+		Name string
+		Tags []string
+	}
+	const input = "Name,Tag1,Tag2,Tag3\nJohn,admin,staff,\nSusan,guest,,"
+	r := csv.NewReader(strings.NewReader(input))
 	r.FieldsPerRecord = -1
+	decoder := NewDecoder(r)
+
+	if err := decoder.ReadHeader(); err != nil {
+		fmt.Printf("%v", err)
+		return
+	}
+	for {
+		var rec Record
+		if err := decoder.Decode(&rec); err == io.EOF {
+			break
+		} else if err != nil {
+			fmt.Printf("%v", err)
+			return
+		}
+		fmt.Printf("Name: %s, Tags: %v\n", rec.Name, rec.Tags)
+	}
+	// Output: Name: John, Tags: [admin staff ]
+	// Name: Susan, Tags: [guest  ]
+}
 
+// The example shows how to use attributes to specify the time format to parse datetime values
+func ExampleDecoder_Decode_time() {
+	type Student struct {
+		Name     string
+		Birthday time.Time `csv:",2006-01-02"`
+	}
+	const input = "John,1994-05-14\nSusan,1991-12-03"
+	r := csv.NewReader(strings.NewReader(input))
 	decoder := NewDecoder(r)
-	var summary Summary
 
-	studentCount := int(0)
-Loop:
 	for {
 		var student Student
-		err := decoder.Decode(&student)
-		switch err {
-		case io.EOF:
-			break Loop
-		case ErrFieldCountMismatch:
-			err = decoder.Retry(&summary)
-			if err == nil {
-				if summary.Count != studentCount {
-					fmt.Printf("Counted %d students, expected %d\n", studentCount, summary.Count)
-				}
-				fmt.Printf("Record count %d\n", studentCount)
-			} else {
-				fmt.Printf("y%v", err)
-			}
-		case nil:
-			fmt.Printf("Name: %s, Birthday: %s\n", student.Name, student.Birthday.Format("Jan 2, 2006"))
-			studentCount += 1
-		default:
-			fmt.Printf("x%v", err)
+		if err := decoder.Decode(&student); err == io.EOF {
+			break
+		} else if err != nil {
+			fmt.Printf("%v", err)
 			return
 		}
+		fmt.Printf("Name: %s, Birthday: %s\n", student.Name, student.Birthday.Format("Jan 2, 2006"))
 	}
 	// Output: Name: John, Birthday: May 14, 1994
 	// Name: Susan, Birthday: Dec 3, 1991
-	// Counted 2 students, expected 3
-	// Record count 2
+}
+
+// The example shows how to encode structs back into CSV, writing a header row derived
+// from the struct's field names before writing the records themselves.
+func ExampleEncoder_Encode() {
+	type Student struct {
+		Name string
+		Age  int
+	}
+	students := []Student{{"John", 21}, {"Susan", 24}}
+
+	w := csv.NewWriter(os.Stdout)
+	encoder := NewEncoder(w)
+
+	if err := encoder.WriteHeader(Student{}); err != nil {
+		fmt.Printf("%v", err)
+		return
+	}
+	for _, student := range students {
+		if err := encoder.Encode(student); err != nil {
+			fmt.Printf("%v", err)
+			return
+		}
+	}
+	w.Flush()
+	// Output: Name,Age
+	// John,21
+	// Susan,24
+}
+
+// The example shows how to decode an entire CSV document at once into a slice of structs, using
+// Unmarshal. The first line is read as a header and matched against the struct's field names.
+func ExampleUnmarshal() {
+	type Student struct {
+		Name string
+		Age  int
+	}
+	const input = "Name,Age\nJohn,21\nSusan,24"
+
+	var students []Student
+	if err := Unmarshal([]byte(input), &students); err != nil {
+		fmt.Printf("%v", err)
+		return
+	}
+	for _, student := range students {
+		fmt.Printf("Name: %s, Age: %d\n", student.Name, student.Age)
+	}
+	// Output: Name: John, Age: 21
+	// Name: Susan, Age: 24
 }